@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/naki0227/nue/gateway/pipeline"
+)
+
+// registerJobRoutes wires GET /jobs/:id (raw job state) and
+// GET /files/:uuid (the file's final metadata once processing
+// finishes), both backed by the same pipeline.Manager job record.
+func registerJobRoutes(r *gin.Engine, manager *pipeline.Manager) {
+	r.GET("/jobs/:id", func(c *gin.Context) {
+		job, ok := manager.GetJob(c.Param("id"))
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+			return
+		}
+		c.JSON(http.StatusOK, job)
+	})
+
+	r.GET("/files/:uuid", func(c *gin.Context) {
+		job, ok := manager.GetJob(c.Param("uuid"))
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		}
+		if job.Status != pipeline.StatusDone {
+			c.JSON(http.StatusOK, gin.H{"status": job.Status})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"uuid":     job.File.UUID,
+			"status":   job.Status,
+			"metadata": job.Result,
+		})
+	})
+}
@@ -1,15 +1,15 @@
 package main
 
 import (
-	"fmt"
-	"net/http"
+	"context"
 	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"go.uber.org/zap"
+
+	"github.com/naki0227/nue/gateway/pipeline"
+	"github.com/naki0227/nue/gateway/storage"
 )
 
 func main() {
@@ -17,8 +17,15 @@ func main() {
 	logger, _ := zap.NewProduction()
 	defer logger.Sync()
 
+	shutdownTracing, err := initTracing(context.Background())
+	if err != nil {
+		logger.Fatal("tracing_init_failed", zap.Error(err))
+	}
+	defer shutdownTracing(context.Background())
+
 	// Create a gin router with custom logging middleware to use Zap
 	r := gin.New()
+	r.Use(tracingMiddleware())
 	r.Use(func(c *gin.Context) {
 		start := time.Now()
 		c.Next()
@@ -34,10 +41,14 @@ func main() {
 	r.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT")
-
-		if c.Request.Method == "OPTIONS" {
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, Tus-Resumable, Upload-Length, Upload-Offset, Upload-Metadata")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, PATCH, HEAD, DELETE")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "Location, Upload-Offset, Upload-Length, Tus-Resumable, Tus-Version, Tus-Extension")
+
+		// /upload's own OPTIONS route (tusOptionsHandler) answers tus
+		// protocol discovery requests with Tus-Resumable/Version/Extension;
+		// let those through instead of short-circuiting here.
+		if c.Request.Method == "OPTIONS" && c.Request.URL.Path != "/upload" {
 			c.AbortWithStatus(204)
 			return
 		}
@@ -51,49 +62,32 @@ func main() {
 		os.MkdirAll(uploadPath, 0755)
 	}
 
-	r.POST("/upload", func(c *gin.Context) {
-		file, err := c.FormFile("file")
-		if err != nil {
-			logger.Error("upload_failed", zap.Error(err))
-			c.JSON(http.StatusBadRequest, gin.H{"error": "No file is received"})
-			return
-		}
-
-		// Get metadata if provided
-		metadata := c.PostForm("metadata")
+	storageBackend, err := storage.NewBackendFromEnv()
+	if err != nil {
+		logger.Fatal("storage_backend_init_failed", zap.Error(err))
+	}
 
-		ext := filepath.Ext(file.Filename)
-		newFilename := uuid.New().String() + ext
-		dst := filepath.Join(uploadPath, newFilename)
+	pipelineManager, err := pipeline.NewManager(logger)
+	if err != nil {
+		logger.Fatal("pipeline_init_failed", zap.Error(err))
+	}
+	pipelineCtx, cancelPipeline := context.WithCancel(context.Background())
+	defer cancelPipeline()
+	pipelineManager.Start(pipelineCtx)
 
-		if err := c.SaveUploadedFile(file, dst); err != nil {
-			logger.Error("save_failed", zap.String("filename", newFilename), zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Unable to save file: %s", err.Error())})
-			return
-		}
+	registerTusRoutes(r, uploadPath, pipelineManager, logger)
+	registerRemoteUploadRoutes(pipelineCtx, r, storageBackend, uploadPath, pipelineManager, logger)
+	registerJobRoutes(r, pipelineManager)
+	registerMetricsRoute(r)
 
-		// Save metadata if provided
-		if metadata != "" {
-			metadataPath := filepath.Join(uploadPath, newFilename+"_metadata.json")
-			if err := os.WriteFile(metadataPath, []byte(metadata), 0644); err != nil {
-				logger.Error("metadata_save_failed", zap.String("filename", newFilename), zap.Error(err))
-				// Don't fail the whole upload if metadata save fails
-			} else {
-				logger.Info("metadata_saved", zap.String("filename", newFilename))
+	r.POST("/upload", func(c *gin.Context) {
+		instrumentUploadRequest(func() {
+			if c.GetHeader("Tus-Resumable") != "" {
+				tusCreateHandler(c, uploadPath, pipelineManager, logger)
+				return
 			}
-		}
-
-		logger.Info("file_received",
-			zap.String("original_name", file.Filename),
-			zap.String("stored_name", newFilename),
-			zap.Bool("has_metadata", metadata != ""),
-		)
 
-		// 202 Accepted because processing happens asynchronously by other services
-		c.JSON(http.StatusAccepted, gin.H{
-			"message":  "File uploaded successfully",
-			"filename": newFilename,
-			"status":   "processing_started",
+			handleStreamingUpload(c, uploadPath, pipelineManager, logger)
 		})
 	})
 
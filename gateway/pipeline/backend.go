@@ -0,0 +1,220 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/redis/go-redis/v9"
+)
+
+func encodeFileRef(file FileRef) ([]byte, error) {
+	return json.Marshal(file)
+}
+
+func decodeFileRef(data []byte, file *FileRef) error {
+	return json.Unmarshal(data, file)
+}
+
+// Backend decouples the Manager from how jobs actually move between the
+// API process and the worker(s): an in-memory channel is enough for a
+// single dev instance, Redis Streams or NATS JetStream let workers run
+// as a separate, horizontally-scaled fleet in production.
+type Backend interface {
+	Enqueue(ctx context.Context, file FileRef) error
+	Dequeue(ctx context.Context) (FileRef, error)
+}
+
+// newBackendFromEnv selects the backend via JOB_QUEUE_BACKEND
+// (memory|redis|nats, default memory).
+func newBackendFromEnv() (Backend, error) {
+	switch os.Getenv("JOB_QUEUE_BACKEND") {
+	case "", "memory":
+		return newMemoryBackend(), nil
+	case "redis":
+		return newRedisStreamBackend()
+	case "nats":
+		return newJetStreamBackend()
+	default:
+		return nil, fmt.Errorf("unknown JOB_QUEUE_BACKEND %q", os.Getenv("JOB_QUEUE_BACKEND"))
+	}
+}
+
+// memoryBackend is a buffered channel; it's the default and is what
+// every dev and test run uses.
+type memoryBackend struct {
+	jobs chan FileRef
+}
+
+func newMemoryBackend() *memoryBackend {
+	return &memoryBackend{jobs: make(chan FileRef, 256)}
+}
+
+func (b *memoryBackend) Enqueue(ctx context.Context, file FileRef) error {
+	select {
+	case b.jobs <- file:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *memoryBackend) Dequeue(ctx context.Context) (FileRef, error) {
+	select {
+	case file := <-b.jobs:
+		return file, nil
+	case <-ctx.Done():
+		return FileRef{}, ctx.Err()
+	}
+}
+
+const redisStreamKey = "nue:uploads"
+
+type redisStreamBackend struct {
+	client *redis.Client
+	group  string
+}
+
+// newRedisStreamBackend builds a backend from REDIS_ADDR (and optional
+// REDIS_PASSWORD); it consumes the stream nue:uploads as a single
+// consumer group so multiple worker processes share the load.
+func newRedisStreamBackend() (*redisStreamBackend, error) {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		return nil, fmt.Errorf("REDIS_ADDR is required when JOB_QUEUE_BACKEND=redis")
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: os.Getenv("REDIS_PASSWORD"),
+	})
+
+	group := "pipeline-workers"
+	if err := client.XGroupCreateMkStream(context.Background(), redisStreamKey, group, "$").Err(); err != nil && !isRedisBusyGroupErr(err) {
+		return nil, fmt.Errorf("creating redis consumer group: %w", err)
+	}
+
+	return &redisStreamBackend{client: client, group: group}, nil
+}
+
+func isRedisBusyGroupErr(err error) bool {
+	return err != nil && err.Error() == "BUSYGROUP Consumer Group name already exists"
+}
+
+func (b *redisStreamBackend) Enqueue(ctx context.Context, file FileRef) error {
+	return b.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: redisStreamKey,
+		Values: map[string]interface{}{
+			"uuid":          file.UUID,
+			"original_name": file.OriginalName,
+			"mime":          file.Mime,
+			"metadata":      file.Metadata,
+			"path":          file.Path,
+		},
+	}).Err()
+}
+
+func (b *redisStreamBackend) Dequeue(ctx context.Context) (FileRef, error) {
+	res, err := b.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    b.group,
+		Consumer: "worker",
+		Streams:  []string{redisStreamKey, ">"},
+		Count:    1,
+		Block:    0,
+	}).Result()
+	if err != nil {
+		return FileRef{}, err
+	}
+
+	msg := res[0].Messages[0]
+	file := FileRef{
+		UUID:         fmt.Sprint(msg.Values["uuid"]),
+		OriginalName: fmt.Sprint(msg.Values["original_name"]),
+		Mime:         fmt.Sprint(msg.Values["mime"]),
+		Metadata:     fmt.Sprint(msg.Values["metadata"]),
+		Path:         fmt.Sprint(msg.Values["path"]),
+	}
+
+	b.client.XAck(ctx, redisStreamKey, b.group, msg.ID)
+	return file, nil
+}
+
+const jetStreamSubject = "nue.uploads"
+
+type jetStreamBackend struct {
+	js      jetstream.JetStream
+	consume jetstream.ConsumeContext
+	jobs    chan FileRef
+}
+
+// newJetStreamBackend builds a backend from NATS_URL, publishing to and
+// consuming from the durable stream "NUE_UPLOADS".
+func newJetStreamBackend() (*jetStreamBackend, error) {
+	url := os.Getenv("NATS_URL")
+	if url == "" {
+		return nil, fmt.Errorf("NATS_URL is required when JOB_QUEUE_BACKEND=nats")
+	}
+
+	nc, err := nats.Connect(url)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to nats: %w", err)
+	}
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, fmt.Errorf("creating jetstream context: %w", err)
+	}
+
+	ctx := context.Background()
+	stream, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     "NUE_UPLOADS",
+		Subjects: []string{jetStreamSubject},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating jetstream stream: %w", err)
+	}
+
+	consumer, err := stream.CreateOrUpdateConsumer(ctx, jetstream.ConsumerConfig{
+		Durable: "pipeline-workers",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating jetstream consumer: %w", err)
+	}
+
+	b := &jetStreamBackend{js: js, jobs: make(chan FileRef, 256)}
+
+	consume, err := consumer.Consume(func(msg jetstream.Msg) {
+		var file FileRef
+		if err := decodeFileRef(msg.Data(), &file); err == nil {
+			b.jobs <- file
+		}
+		msg.Ack()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("starting jetstream consume: %w", err)
+	}
+	b.consume = consume
+
+	return b, nil
+}
+
+func (b *jetStreamBackend) Enqueue(ctx context.Context, file FileRef) error {
+	data, err := encodeFileRef(file)
+	if err != nil {
+		return err
+	}
+	_, err = b.js.Publish(ctx, jetStreamSubject, data)
+	return err
+}
+
+func (b *jetStreamBackend) Dequeue(ctx context.Context) (FileRef, error) {
+	select {
+	case file := <-b.jobs:
+		return file, nil
+	case <-ctx.Done():
+		return FileRef{}, ctx.Err()
+	}
+}
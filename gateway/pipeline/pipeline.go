@@ -0,0 +1,246 @@
+// Package pipeline runs post-upload processing asynchronously. A Manager
+// enqueues a FileRef onto a pluggable Backend once a file has finished
+// being saved, and a pool of workers pulls jobs off that backend and
+// runs them through every registered Processor, recording state that
+// GET /jobs/:id and GET /files/:uuid can observe.
+package pipeline
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type Status string
+
+const (
+	StatusQueued  Status = "queued"
+	StatusRunning Status = "running"
+	StatusDone    Status = "done"
+	StatusFailed  Status = "failed"
+)
+
+// Defaults for the jobs map eviction applied by evictStaleJobs: terminal
+// jobs older than the TTL are dropped, and the map is capped at maxJobs
+// regardless of age so a long-running instance can't leak memory
+// indefinitely.
+const (
+	defaultJobTTL     = 24 * time.Hour
+	defaultMaxJobs    = 100000
+	evictLoopInterval = 5 * time.Minute
+)
+
+func jobTTL() time.Duration {
+	if raw := os.Getenv("JOB_TTL"); raw != "" {
+		if d, err := time.ParseDuration(raw); err == nil {
+			return d
+		}
+	}
+	return defaultJobTTL
+}
+
+func maxJobs() int {
+	if raw := os.Getenv("MAX_TRACKED_JOBS"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultMaxJobs
+}
+
+// FileRef is the description of a newly-saved file a Processor needs to
+// do its work; it carries nothing beyond what's already on disk under
+// uploadPath so processors don't need direct access to the gin handler.
+type FileRef struct {
+	UUID         string `json:"uuid"`
+	OriginalName string `json:"original_name"`
+	Mime         string `json:"mime"`
+	Metadata     string `json:"metadata,omitempty"`
+	Path         string `json:"-"`
+}
+
+// Processor is implemented by each pipeline stage. Operators register
+// their own alongside the reference implementations by passing
+// additional Processors to NewManager; the reference ones always run
+// first and cannot be replaced.
+type Processor interface {
+	Name() string
+	Process(ctx context.Context, file FileRef) (map[string]interface{}, error)
+}
+
+// Job tracks one FileRef's progress through every registered Processor.
+// Its ID is the file's UUID, so GET /jobs/:id and GET /files/:uuid look
+// up the same record.
+type Job struct {
+	ID     string                 `json:"id"`
+	File   FileRef                `json:"file"`
+	Status Status                 `json:"status"`
+	Result map[string]interface{} `json:"result,omitempty"`
+	Error  string                 `json:"error,omitempty"`
+
+	// UpdatedAt drives evictStaleJobs and isn't part of the public API.
+	UpdatedAt time.Time `json:"-"`
+}
+
+type Manager struct {
+	backend    Backend
+	processors []Processor
+	logger     *zap.Logger
+
+	mu   sync.RWMutex
+	jobs map[string]*Job
+}
+
+// NewManager selects the queue backend from JOB_QUEUE_BACKEND
+// (memory|redis|nats, default memory) and registers the reference
+// processors alongside any extra ones passed in.
+func NewManager(logger *zap.Logger, extra ...Processor) (*Manager, error) {
+	backend, err := newBackendFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	processors := append([]Processor{NewThumbnailEXIFProcessor(), NewVirusScanStubProcessor()}, extra...)
+
+	return &Manager{
+		backend:    backend,
+		processors: processors,
+		logger:     logger,
+		jobs:       make(map[string]*Job),
+	}, nil
+}
+
+// Start launches the worker loop that drains the backend; it returns
+// once ctx is canceled.
+func (m *Manager) Start(ctx context.Context) {
+	go func() {
+		for {
+			file, err := m.backend.Dequeue(ctx)
+			if err != nil {
+				return // ctx canceled
+			}
+			m.runJob(ctx, file)
+		}
+	}()
+	go m.evictLoop(ctx)
+}
+
+// evictLoop periodically prunes m.jobs so a long-running instance
+// doesn't leak memory; it returns once ctx is canceled.
+func (m *Manager) evictLoop(ctx context.Context) {
+	ticker := time.NewTicker(evictLoopInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.evictStaleJobs()
+		}
+	}
+}
+
+// evictStaleJobs drops terminal jobs (done/failed) older than jobTTL,
+// then, if the map is still over maxJobs, drops the oldest remaining
+// entries regardless of status until it's back at the cap.
+func (m *Manager) evictStaleJobs() {
+	ttl := jobTTL()
+	limit := maxJobs()
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, job := range m.jobs {
+		if (job.Status == StatusDone || job.Status == StatusFailed) && now.Sub(job.UpdatedAt) > ttl {
+			delete(m.jobs, id)
+		}
+	}
+
+	if len(m.jobs) <= limit {
+		return
+	}
+
+	ids := make([]string, 0, len(m.jobs))
+	for id := range m.jobs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return m.jobs[ids[i]].UpdatedAt.Before(m.jobs[ids[j]].UpdatedAt) })
+	for _, id := range ids[:len(ids)-limit] {
+		delete(m.jobs, id)
+	}
+}
+
+// Enqueue records a queued Job for file and publishes it to the
+// backend, returning the job ID (the file's UUID).
+func (m *Manager) Enqueue(ctx context.Context, file FileRef) (string, error) {
+	job := &Job{ID: file.UUID, File: file, Status: StatusQueued}
+	m.saveJob(job)
+
+	if err := m.backend.Enqueue(ctx, file); err != nil {
+		job.Status = StatusFailed
+		job.Error = err.Error()
+		m.saveJob(job)
+		return "", err
+	}
+	return job.ID, nil
+}
+
+// GetJob returns the job tracked for id (a file UUID), if any.
+func (m *Manager) GetJob(id string) (*Job, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	job, ok := m.jobs[id]
+	return job, ok
+}
+
+func (m *Manager) saveJob(job *Job) {
+	job.UpdatedAt = time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jobs[job.ID] = job
+}
+
+// RecordExternal records a terminal Job for file without running it
+// through any Processor. It's for ingestion paths where the bytes never
+// land on local disk (e.g. the pre-signed direct-to-object-storage
+// upload) and a Processor would have nothing to read, so GET /jobs/:id
+// and GET /files/:uuid still resolve instead of 404ing.
+func (m *Manager) RecordExternal(file FileRef, note string) {
+	m.saveJob(&Job{
+		ID:     file.UUID,
+		File:   file,
+		Status: StatusDone,
+		Result: map[string]interface{}{"note": note},
+	})
+}
+
+func (m *Manager) runJob(ctx context.Context, file FileRef) {
+	job := &Job{ID: file.UUID, File: file, Status: StatusRunning}
+	m.saveJob(job)
+
+	result := map[string]interface{}{}
+	for _, p := range m.processors {
+		out, err := p.Process(ctx, file)
+		if err != nil {
+			job.Status = StatusFailed
+			job.Error = err.Error()
+			m.saveJob(job)
+			m.logger.Error("pipeline_stage_failed", zap.String("processor", p.Name()), zap.String("uuid", file.UUID), zap.Error(err))
+			return
+		}
+		for k, v := range out {
+			result[k] = v
+		}
+	}
+
+	job.Status = StatusDone
+	job.Result = result
+	m.saveJob(job)
+	m.logger.Info("pipeline_job_completed", zap.String("uuid", file.UUID))
+}
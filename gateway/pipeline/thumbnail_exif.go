@@ -0,0 +1,73 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+
+	"github.com/nfnt/resize"
+	"github.com/rwcarlsen/goexif/exif"
+)
+
+const thumbnailMaxDimension = 256
+
+// thumbnailEXIFProcessor is the reference image-processing stage: it
+// decodes the saved file, writes a bounded-size thumbnail next to it,
+// and pulls out EXIF tags when present. Non-image files are skipped
+// rather than failing the job.
+type thumbnailEXIFProcessor struct{}
+
+func NewThumbnailEXIFProcessor() Processor {
+	return &thumbnailEXIFProcessor{}
+}
+
+func (p *thumbnailEXIFProcessor) Name() string { return "thumbnail_exif" }
+
+func (p *thumbnailEXIFProcessor) Process(ctx context.Context, file FileRef) (map[string]interface{}, error) {
+	f, err := os.Open(file.Path)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", file.Path, err)
+	}
+	defer f.Close()
+
+	img, format, err := image.Decode(f)
+	if err != nil {
+		// Not a decodable image (e.g. video, pdf) - nothing for this stage to do.
+		return map[string]interface{}{"thumbnail": nil}, nil
+	}
+
+	thumb := resize.Thumbnail(thumbnailMaxDimension, thumbnailMaxDimension, img, resize.Lanczos3)
+	thumbPath := filepath.Join(filepath.Dir(file.Path), file.UUID+"_thumb.jpg")
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, fmt.Errorf("encoding thumbnail: %w", err)
+	}
+	if err := os.WriteFile(thumbPath, buf.Bytes(), 0644); err != nil {
+		return nil, fmt.Errorf("writing thumbnail: %w", err)
+	}
+
+	result := map[string]interface{}{
+		"thumbnail": filepath.Base(thumbPath),
+		"format":    format,
+	}
+
+	if format == "jpeg" {
+		if _, err := f.Seek(0, 0); err == nil {
+			if x, err := exif.Decode(f); err == nil {
+				if tags, err := json.Marshal(x); err == nil {
+					result["exif"] = json.RawMessage(tags)
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,20 @@
+package pipeline
+
+import "context"
+
+// virusScanStubProcessor is a placeholder reference implementation:
+// it always reports the file as clean. Operators wanting real scanning
+// should register their own Processor (e.g. one that streams the file
+// to ClamAV) via NewManager's extra argument instead of relying on this
+// stub in production.
+type virusScanStubProcessor struct{}
+
+func NewVirusScanStubProcessor() Processor {
+	return &virusScanStubProcessor{}
+}
+
+func (p *virusScanStubProcessor) Name() string { return "virus_scan_stub" }
+
+func (p *virusScanStubProcessor) Process(ctx context.Context, file FileRef) (map[string]interface{}, error) {
+	return map[string]interface{}{"virus_scan": "skipped_stub"}, nil
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/naki0227/nue/gateway/pipeline"
+	"github.com/naki0227/nue/gateway/storage"
+)
+
+// authorizedKeysReapInterval is how often reapExpired sweeps for
+// presigned keys whose expiresAt has passed without a matching
+// /upload/finalize - e.g. an abandoned client, or someone hammering
+// /upload/authorize - so the map doesn't grow without bound.
+const authorizedKeysReapInterval = 5 * time.Minute
+
+// remoteUploadStub is the manifest entry left in uploadPath when a file
+// was uploaded directly to object storage rather than through this
+// service; it mirrors the "<uuid><ext>_metadata.json" sidecar the local
+// upload path writes, but points at the remote object instead of bytes
+// on disk.
+type remoteUploadStub struct {
+	Backend          string `json:"backend"`
+	Key              string `json:"key"`
+	ETag             string `json:"etag"`
+	OriginalFilename string `json:"original_filename"`
+	Metadata         string `json:"metadata,omitempty"`
+	FinalizedAt      string `json:"finalized_at"`
+}
+
+// authorizedKeys tracks the object keys this service has handed out a
+// pre-signed PUT URL for via POST /upload/authorize, so POST
+// /upload/finalize can refuse to write a stub for a key the client
+// invented itself rather than one this service issued.
+type authorizedKeys struct {
+	mu   sync.Mutex
+	keys map[string]time.Time
+}
+
+func newAuthorizedKeys() *authorizedKeys {
+	return &authorizedKeys{keys: make(map[string]time.Time)}
+}
+
+func (a *authorizedKeys) add(key string, expiresAt time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.keys[key] = expiresAt
+}
+
+// consume reports whether key was authorized and not yet used, removing
+// it so a key can only be finalized once.
+func (a *authorizedKeys) consume(key string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	expiresAt, ok := a.keys[key]
+	delete(a.keys, key)
+	return ok && time.Now().Before(expiresAt)
+}
+
+// reapExpired drops every tracked key whose expiresAt has already
+// passed, analogous to pipeline.Manager.evictStaleJobs.
+func (a *authorizedKeys) reapExpired() {
+	now := time.Now()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for key, expiresAt := range a.keys {
+		if now.After(expiresAt) {
+			delete(a.keys, key)
+		}
+	}
+}
+
+// reapLoop periodically calls reapExpired until ctx is canceled.
+func (a *authorizedKeys) reapLoop(ctx context.Context) {
+	ticker := time.NewTicker(authorizedKeysReapInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.reapExpired()
+		}
+	}
+}
+
+// registerRemoteUploadRoutes wires POST /upload/authorize and
+// POST /upload/finalize. backend is nil when STORAGE_BACKEND is unset
+// or "local", in which case both endpoints report 501 and direct
+// callers back to POST /upload. ctx governs the background reaper that
+// sweeps expired, unconsumed authorize keys; it should be canceled at
+// shutdown alongside the rest of the service's background work.
+func registerRemoteUploadRoutes(ctx context.Context, r *gin.Engine, backend storage.Backend, uploadPath string, manager *pipeline.Manager, logger *zap.Logger) {
+	authorized := newAuthorizedKeys()
+	if backend != nil {
+		go authorized.reapLoop(ctx)
+	}
+
+	r.POST("/upload/authorize", func(c *gin.Context) {
+		if backend == nil {
+			c.JSON(http.StatusNotImplemented, gin.H{"error": "STORAGE_BACKEND is local; use POST /upload instead"})
+			return
+		}
+
+		var req struct {
+			Filename    string `json:"filename" binding:"required"`
+			ContentType string `json:"content_type"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		key := uuid.New().String() + filepath.Ext(req.Filename)
+		contentType := req.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+
+		put, err := backend.PresignPut(c.Request.Context(), key, contentType)
+		if err != nil {
+			logger.Error("presign_failed", zap.String("backend", backend.Name()), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Unable to presign upload: %s", err.Error())})
+			return
+		}
+
+		authorized.add(key, put.ExpiresAt)
+		logger.Info("upload_authorized", zap.String("backend", backend.Name()), zap.String("key", key))
+
+		c.JSON(http.StatusOK, gin.H{
+			"upload_url": put.URL,
+			"method":     put.Method,
+			"headers":    put.Headers,
+			"key":        put.Key,
+			"backend":    backend.Name(),
+			"expires_at": put.ExpiresAt,
+		})
+	})
+
+	r.POST("/upload/finalize", func(c *gin.Context) {
+		if backend == nil {
+			c.JSON(http.StatusNotImplemented, gin.H{"error": "STORAGE_BACKEND is local; use POST /upload instead"})
+			return
+		}
+
+		var req struct {
+			Key              string `json:"key" binding:"required"`
+			ETag             string `json:"etag" binding:"required"`
+			OriginalFilename string `json:"original_filename"`
+			Metadata         string `json:"metadata"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if strings.ContainsAny(req.Key, `/\`) || strings.Contains(req.Key, "..") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid key"})
+			return
+		}
+		if !authorized.consume(req.Key) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "key was not authorized via POST /upload/authorize"})
+			return
+		}
+
+		stub := remoteUploadStub{
+			Backend:          backend.Name(),
+			Key:              req.Key,
+			ETag:             req.ETag,
+			OriginalFilename: req.OriginalFilename,
+			Metadata:         req.Metadata,
+			FinalizedAt:      time.Now().UTC().Format(time.RFC3339),
+		}
+
+		raw, err := json.Marshal(stub)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		stubPath := filepath.Join(uploadPath, req.Key+".remote.json")
+		if err := os.WriteFile(stubPath, raw, 0644); err != nil {
+			logger.Error("remote_stub_save_failed", zap.String("key", req.Key), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Unable to record upload: %s", err.Error())})
+			return
+		}
+
+		logger.Info("upload_finalized", zap.String("backend", backend.Name()), zap.String("key", req.Key))
+
+		recordUpload("success", "", 0)
+		setUploadSpanAttributes(c, req.OriginalFilename, req.Key, 0, req.Metadata != "")
+
+		// The bytes live in object storage, not on local disk, so no
+		// Processor can run against them - record a terminal job anyway
+		// so GET /jobs/:id and GET /files/:uuid behave the same as every
+		// other ingestion path instead of 404ing for these uploads.
+		fileUUID := strings.TrimSuffix(req.Key, filepath.Ext(req.Key))
+		manager.RecordExternal(
+			pipeline.FileRef{UUID: fileUUID, OriginalName: req.OriginalFilename, Metadata: req.Metadata},
+			"uploaded directly to "+backend.Name()+"; not processed by the local pipeline",
+		)
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"message": "File recorded successfully",
+			"key":     req.Key,
+			"backend": backend.Name(),
+			"status":  "processing_started",
+		})
+	})
+}
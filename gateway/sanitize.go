@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Validation/sanitization gate run on every part after it's sniffed but
+// before it's persisted: reject files whose real MIME isn't in the
+// allow-list (regardless of extension or client Content-Type), strip
+// EXIF/GPS from images once they're on disk, and optionally run the
+// completed file through ClamAV, quarantining anything infected.
+
+var defaultAllowedMimePrefixes = []string{"image/", "video/", "audio/", "application/pdf"}
+
+var errMimeNotAllowed = fmt.Errorf("mime type not allowed")
+var errInfected = fmt.Errorf("file is infected")
+
+// sniffReader peeks the first 512 bytes of r (http.DetectContentType
+// only looks at that many) and returns the sniffed MIME plus a reader
+// that still yields every byte, peeked ones included.
+func sniffReader(r io.Reader) (string, io.Reader, error) {
+	buf := bufio.NewReaderSize(r, 512)
+	peeked, err := buf.Peek(512)
+	if err != nil && err != io.EOF && len(peeked) == 0 {
+		return "", nil, err
+	}
+	return http.DetectContentType(peeked), buf, nil
+}
+
+func isAllowedMime(mime string) bool {
+	allowList := os.Getenv("UPLOAD_ALLOWED_MIME_TYPES")
+	if allowList == "" {
+		for _, prefix := range defaultAllowedMimePrefixes {
+			if strings.HasPrefix(mime, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, allowed := range strings.Split(allowList, ",") {
+		if strings.TrimSpace(allowed) == mime {
+			return true
+		}
+	}
+	return false
+}
+
+// scrubEXIF re-encodes JPEG/PNG images in place, which drops EXIF, GPS
+// and any other ancillary metadata the original file carried since
+// Go's image codecs never round-trip it. HEIC has no metadata-bearing
+// standard library codec, so it's left untouched.
+func scrubEXIF(path, mime string) error {
+	if mime != "image/jpeg" && mime != "image/png" {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	img, _, err := image.Decode(f)
+	f.Close()
+	if err != nil {
+		// Not a decodable image after all - leave it as uploaded.
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if mime == "image/jpeg" {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95})
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// clamavScan speaks the ClamAV INSTREAM protocol: a stream of
+// 4-byte-length-prefixed chunks terminated by a zero-length chunk,
+// followed by a single line reply.
+func clamavScan(addr string, r io.Reader) (bool, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return false, fmt.Errorf("dialing clamav: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return false, err
+	}
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			var lenPrefix [4]byte
+			putUint32BE(lenPrefix[:], uint32(n))
+			if _, err := conn.Write(lenPrefix[:]); err != nil {
+				return false, err
+			}
+			if _, err := conn.Write(buf[:n]); err != nil {
+				return false, err
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return false, readErr
+		}
+	}
+
+	var zeroLen [4]byte
+	if _, err := conn.Write(zeroLen[:]); err != nil {
+		return false, err
+	}
+
+	reply, err := io.ReadAll(conn)
+	if err != nil {
+		return false, err
+	}
+
+	return strings.Contains(string(reply), "FOUND"), nil
+}
+
+func putUint32BE(b []byte, v uint32) {
+	b[0] = byte(v >> 24)
+	b[1] = byte(v >> 16)
+	b[2] = byte(v >> 8)
+	b[3] = byte(v)
+}
+
+// postWriteGate runs the ClamAV scan (if CLAMAV_ADDR is configured) and
+// EXIF scrub against a file already written to disk at path, whose mime
+// has already been sniffed and allow-listed by the caller. storedName is
+// the file's name under uploadPath, used to quarantine it if infected.
+// This is shared by both ingestion paths that write bytes to local disk
+// (streaming multipart, tus) so neither can bypass the gate. Files
+// recorded via POST /upload/finalize never pass through this service and
+// are not gated.
+func postWriteGate(path, mime, uploadPath, storedName string, logger *zap.Logger) error {
+	if addr := os.Getenv("CLAMAV_ADDR"); addr != "" {
+		scanFile, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		infected, err := clamavScan(addr, scanFile)
+		scanFile.Close()
+		if err != nil {
+			return err
+		}
+		if infected {
+			if err := quarantineFile(uploadPath, storedName, logger); err != nil {
+				logger.Error("quarantine_failed", zap.String("filename", storedName), zap.Error(err))
+			}
+			return errInfected
+		}
+	}
+
+	if err := scrubEXIF(path, mime); err != nil {
+		logger.Error("exif_scrub_failed", zap.String("filename", storedName), zap.Error(err))
+	}
+	return nil
+}
+
+// quarantineFile moves an infected upload under uploadPath/quarantine
+// so it's kept for incident review but out of the normal serving path.
+func quarantineFile(uploadPath, storedName string, logger *zap.Logger) error {
+	quarantineDir := filepath.Join(uploadPath, "quarantine")
+	if err := os.MkdirAll(quarantineDir, 0755); err != nil {
+		return err
+	}
+
+	src := filepath.Join(uploadPath, storedName)
+	dst := filepath.Join(quarantineDir, storedName)
+	if err := os.Rename(src, dst); err != nil {
+		return err
+	}
+
+	logger.Warn("file_quarantined", zap.String("filename", storedName))
+	return nil
+}
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/service"
+)
+
+const azurePresignExpiry = 15 * time.Minute
+
+type azureBackend struct {
+	container string
+	client    *service.Client
+}
+
+// newAzureBackendFromEnv builds an Azure Blob backend from
+// AZURE_STORAGE_ACCOUNT, AZURE_STORAGE_CONTAINER and
+// AZURE_STORAGE_KEY (shared key auth, used to sign the SAS URL).
+func newAzureBackendFromEnv() (Backend, error) {
+	account := os.Getenv("AZURE_STORAGE_ACCOUNT")
+	container := os.Getenv("AZURE_STORAGE_CONTAINER")
+	key := os.Getenv("AZURE_STORAGE_KEY")
+	if account == "" || container == "" || key == "" {
+		return nil, fmt.Errorf("AZURE_STORAGE_ACCOUNT, AZURE_STORAGE_CONTAINER and AZURE_STORAGE_KEY are required when STORAGE_BACKEND=azure")
+	}
+
+	serviceURL := fmt.Sprintf("https://%s.blob.core.windows.net/", account)
+	cred, err := service.NewSharedKeyCredential(account, key)
+	if err != nil {
+		return nil, fmt.Errorf("creating azure credential: %w", err)
+	}
+
+	client, err := service.NewClientWithSharedKeyCredential(serviceURL, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating azure client: %w", err)
+	}
+
+	return &azureBackend{container: container, client: client}, nil
+}
+
+func (b *azureBackend) Name() string { return "azure" }
+
+func (b *azureBackend) PresignPut(ctx context.Context, key, contentType string) (*PresignedPut, error) {
+	expiresAt := time.Now().Add(azurePresignExpiry)
+
+	containerClient := b.client.NewContainerClient(b.container)
+	blobClient := containerClient.NewBlobClient(key)
+
+	url, err := blobClient.GetSASURL(sas.BlobPermissions{Write: true, Create: true}, expiresAt, nil)
+	if err != nil {
+		return nil, fmt.Errorf("presigning azure put: %w", err)
+	}
+
+	return &PresignedPut{
+		URL:       url,
+		Method:    "PUT",
+		Headers:   map[string]string{"x-ms-blob-type": "BlockBlob", "Content-Type": contentType},
+		Key:       key,
+		ExpiresAt: expiresAt,
+	}, nil
+}
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"cloud.google.com/go/storage"
+)
+
+const gcsPresignExpiry = 15 * time.Minute
+
+type gcsBackend struct {
+	bucket string
+	client *storage.Client
+}
+
+// newGCSBackendFromEnv builds a GCS backend from GCS_BUCKET; credentials
+// are resolved via GOOGLE_APPLICATION_CREDENTIALS per the standard
+// google-cloud-go default credential chain.
+func newGCSBackendFromEnv() (Backend, error) {
+	bucket := os.Getenv("GCS_BUCKET")
+	if bucket == "" {
+		return nil, fmt.Errorf("GCS_BUCKET is required when STORAGE_BACKEND=gcs")
+	}
+
+	client, err := storage.NewClient(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("creating GCS client: %w", err)
+	}
+
+	return &gcsBackend{bucket: bucket, client: client}, nil
+}
+
+func (b *gcsBackend) Name() string { return "gcs" }
+
+func (b *gcsBackend) PresignPut(ctx context.Context, key, contentType string) (*PresignedPut, error) {
+	expiresAt := time.Now().Add(gcsPresignExpiry)
+
+	url, err := b.client.Bucket(b.bucket).SignedURL(key, &storage.SignedURLOptions{
+		Method:      "PUT",
+		Expires:     expiresAt,
+		ContentType: contentType,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("presigning gcs put: %w", err)
+	}
+
+	return &PresignedPut{
+		URL:       url,
+		Method:    "PUT",
+		Headers:   map[string]string{"Content-Type": contentType},
+		Key:       key,
+		ExpiresAt: expiresAt,
+	}, nil
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const s3PresignExpiry = 15 * time.Minute
+
+type s3Backend struct {
+	bucket  string
+	client  *s3.Client
+	presign *s3.PresignClient
+}
+
+// newS3BackendFromEnv builds an S3 backend from AWS_REGION, S3_BUCKET
+// and the standard AWS credential chain (env vars, shared config,
+// instance role); STORAGE_ACCESS_KEY/STORAGE_SECRET_KEY override the
+// chain when set, for pointing at non-AWS S3-compatible endpoints.
+func newS3BackendFromEnv() (Backend, error) {
+	bucket := os.Getenv("S3_BUCKET")
+	if bucket == "" {
+		return nil, fmt.Errorf("S3_BUCKET is required when STORAGE_BACKEND=s3")
+	}
+
+	opts := []func(*config.LoadOptions) error{config.WithRegion(os.Getenv("AWS_REGION"))}
+	if accessKey, secretKey := os.Getenv("STORAGE_ACCESS_KEY"), os.Getenv("STORAGE_SECRET_KEY"); accessKey != "" && secretKey != "" {
+		creds := aws.Credentials{AccessKeyID: accessKey, SecretAccessKey: secretKey, Source: "STORAGE_ACCESS_KEY"}
+		opts = append(opts, config.WithCredentialsProvider(aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return creds, nil
+		})))
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS config: %w", err)
+	}
+
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if endpoint := os.Getenv("S3_ENDPOINT"); endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+	})
+
+	return &s3Backend{
+		bucket:  bucket,
+		client:  client,
+		presign: s3.NewPresignClient(client),
+	}, nil
+}
+
+func (b *s3Backend) Name() string { return "s3" }
+
+func (b *s3Backend) PresignPut(ctx context.Context, key, contentType string) (*PresignedPut, error) {
+	req, err := b.presign.PresignPutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(b.bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+	}, s3.WithPresignExpires(s3PresignExpiry))
+	if err != nil {
+		return nil, fmt.Errorf("presigning s3 put: %w", err)
+	}
+
+	headers := make(map[string]string, len(req.SignedHeader))
+	for k, v := range req.SignedHeader {
+		if len(v) > 0 {
+			headers[k] = v[0]
+		}
+	}
+
+	return &PresignedPut{
+		URL:       req.URL,
+		Method:    req.Method,
+		Headers:   headers,
+		Key:       key,
+		ExpiresAt: time.Now().Add(s3PresignExpiry),
+	}, nil
+}
@@ -0,0 +1,52 @@
+// Package storage implements the direct-to-object-storage upload path:
+// instead of the gin process buffering every byte of an upload, a client
+// asks a Backend for a pre-signed PUT URL, uploads straight to the
+// bucket, and then hands the object key back to the service to record.
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// PresignedPut is the URL (and any headers the client must send) a
+// client uses to PUT an object directly to the backend.
+type PresignedPut struct {
+	URL       string            `json:"url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Key       string            `json:"key"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// Backend is implemented by every supported object store. Local disk
+// intentionally does not implement it: pre-signed uploads only make
+// sense against a remote bucket, and the local-disk path keeps using
+// the existing POST /upload flow.
+type Backend interface {
+	// Name identifies the backend, e.g. "s3", "gcs", "azure".
+	Name() string
+	// PresignPut returns a URL the client can PUT key's bytes to.
+	PresignPut(ctx context.Context, key, contentType string) (*PresignedPut, error)
+}
+
+// NewBackendFromEnv builds the configured Backend from STORAGE_BACKEND
+// and the backend-specific env vars below. It returns (nil, nil) when
+// STORAGE_BACKEND is unset or "local", since local disk has no
+// pre-signing backend and callers should fall back to POST /upload.
+func NewBackendFromEnv() (Backend, error) {
+	switch os.Getenv("STORAGE_BACKEND") {
+	case "", "local":
+		return nil, nil
+	case "s3":
+		return newS3BackendFromEnv()
+	case "gcs":
+		return newGCSBackendFromEnv()
+	case "azure":
+		return newAzureBackendFromEnv()
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND %q", os.Getenv("STORAGE_BACKEND"))
+	}
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/naki0227/nue/gateway/pipeline"
+)
+
+const (
+	defaultMaxUploadBytesPerPart    = 500 << 20  // 500MiB
+	defaultMaxUploadBytesPerRequest = 2000 << 20 // 2000MiB
+)
+
+// uploadedFile describes one part streamed to disk, returned to the
+// caller alongside the hashes computed while the bytes were written.
+type uploadedFile struct {
+	UUID         string `json:"uuid"`
+	OriginalName string `json:"original_name"`
+	StoredName   string `json:"stored_name"`
+	Mime         string `json:"mime"`
+	Size         int64  `json:"size"`
+	SHA256       string `json:"sha256"`
+	CRC32        string `json:"crc32"`
+}
+
+func maxUploadBytesPerPart() int64 {
+	return envInt64OrDefault("MAX_UPLOAD_BYTES_PER_PART", defaultMaxUploadBytesPerPart)
+}
+
+func maxUploadBytesPerRequest() int64 {
+	return envInt64OrDefault("MAX_UPLOAD_BYTES_PER_REQUEST", defaultMaxUploadBytesPerRequest)
+}
+
+func envInt64OrDefault(key string, fallback int64) int64 {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	v, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || v <= 0 {
+		return fallback
+	}
+	return v
+}
+
+// handleStreamingUpload replaces c.FormFile with a raw multipart.Reader
+// loop so files are written to disk as they arrive rather than buffered
+// into memory (or a temp file) first. It supports multiple "files" parts
+// per request, hashes each part as it streams, and enforces per-part and
+// per-request size limits with a 413.
+func handleStreamingUpload(c *gin.Context, uploadPath string, manager *pipeline.Manager, logger *zap.Logger) {
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		logger.Error("upload_failed", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file is received"})
+		return
+	}
+
+	maxPart := maxUploadBytesPerPart()
+	maxRequest := maxUploadBytesPerRequest()
+
+	var (
+		files        []uploadedFile
+		metadata     string
+		totalWritten int64
+	)
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			cleanupUploadedFiles(uploadPath, files)
+			logger.Error("upload_failed", zap.Error(err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Malformed multipart body: %s", err.Error())})
+			return
+		}
+
+		if part.FileName() == "" {
+			if part.FormName() == "metadata" {
+				buf, _ := io.ReadAll(io.LimitReader(part, 1<<20))
+				metadata = string(buf)
+			}
+			part.Close()
+			continue
+		}
+
+		uploaded, err := streamPartToDisk(part, uploadPath, maxPart, logger)
+		part.Close()
+		if err != nil {
+			cleanupUploadedFiles(uploadPath, files)
+			switch err {
+			case errPartTooLarge:
+				recordUpload("too_large", "", 0)
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Part %q exceeds MAX_UPLOAD_BYTES_PER_PART", part.FileName())})
+			case errMimeNotAllowed:
+				recordUpload("mime_rejected", "", 0)
+				c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": fmt.Sprintf("Part %q has a disallowed MIME type", part.FileName())})
+			case errInfected:
+				recordUpload("infected", "", 0)
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf("Part %q failed virus scan", part.FileName())})
+			default:
+				recordUpload("error", "", 0)
+				logger.Error("save_failed", zap.Error(err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Unable to save file: %s", err.Error())})
+			}
+			return
+		}
+
+		totalWritten += uploaded.Size
+		if totalWritten > maxRequest {
+			cleanupUploadedFiles(uploadPath, append(files, *uploaded))
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request exceeds MAX_UPLOAD_BYTES_PER_REQUEST"})
+			return
+		}
+
+		files = append(files, *uploaded)
+	}
+
+	if len(files) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file is received"})
+		return
+	}
+
+	if contentMD5 := c.GetHeader("Content-MD5"); contentMD5 != "" && len(files) == 1 {
+		if err := verifyContentMD5(filepath.Join(uploadPath, files[0].StoredName), contentMD5); err != nil {
+			cleanupUploadedFiles(uploadPath, files)
+			logger.Error("content_md5_mismatch", zap.String("filename", files[0].StoredName), zap.Error(err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Content-MD5 mismatch"})
+			return
+		}
+	}
+
+	if metadata != "" {
+		for _, f := range files {
+			metadataPath := filepath.Join(uploadPath, f.StoredName+"_metadata.json")
+			if err := os.WriteFile(metadataPath, []byte(metadata), 0644); err != nil {
+				metadataSaveFailuresTotal.Inc()
+				logger.Error("metadata_save_failed", zap.String("filename", f.StoredName), zap.Error(err))
+			} else {
+				logger.Info("metadata_saved", zap.String("filename", f.StoredName))
+			}
+		}
+	}
+
+	for _, f := range files {
+		logger.Info("file_received",
+			zap.String("original_name", f.OriginalName),
+			zap.String("stored_name", f.StoredName),
+			zap.Int64("size", f.Size),
+			zap.Bool("has_metadata", metadata != ""),
+		)
+
+		recordUpload("success", f.Mime, f.Size)
+		setUploadSpanAttributes(c, f.OriginalName, f.UUID, f.Size, metadata != "")
+
+		fileRef := pipeline.FileRef{UUID: f.UUID, OriginalName: f.OriginalName, Mime: f.Mime, Metadata: metadata, Path: filepath.Join(uploadPath, f.StoredName)}
+		if _, err := manager.Enqueue(context.Background(), fileRef); err != nil {
+			logger.Error("pipeline_enqueue_failed", zap.String("uuid", f.UUID), zap.Error(err))
+		}
+	}
+
+	// 202 Accepted because processing happens asynchronously by other services
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "File uploaded successfully",
+		"files":   files,
+		"status":  "processing_started",
+	})
+}
+
+var errPartTooLarge = fmt.Errorf("part exceeds MAX_UPLOAD_BYTES_PER_PART")
+
+func streamPartToDisk(part *multipart.Part, uploadPath string, maxPart int64, logger *zap.Logger) (*uploadedFile, error) {
+	mime, sniffed, err := sniffReader(part)
+	if err != nil {
+		return nil, err
+	}
+	if !isAllowedMime(mime) {
+		return nil, errMimeNotAllowed
+	}
+
+	ext := filepath.Ext(part.FileName())
+	id := uuid.New().String()
+	newFilename := id + ext
+	dst := filepath.Join(uploadPath, newFilename)
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return nil, err
+	}
+
+	written, err := io.Copy(out, io.LimitReader(sniffed, maxPart+1))
+	out.Close()
+	if err != nil {
+		os.Remove(dst)
+		return nil, err
+	}
+	if written > maxPart {
+		os.Remove(dst)
+		return nil, errPartTooLarge
+	}
+
+	if err := postWriteGate(dst, mime, uploadPath, newFilename, logger); err != nil {
+		if err != errInfected {
+			os.Remove(dst)
+		}
+		return nil, err
+	}
+
+	// Hashed (and sized) after scrubEXIF, rather than while streaming to
+	// disk, so both describe the bytes actually persisted rather than the
+	// pre-scrub original - scrubEXIF re-encodes every JPEG/PNG in place,
+	// which changes its size along with its bytes.
+	size, shaHex, crcHex, err := hashFile(dst)
+	if err != nil {
+		os.Remove(dst)
+		return nil, err
+	}
+
+	return &uploadedFile{
+		UUID:         id,
+		OriginalName: part.FileName(),
+		StoredName:   newFilename,
+		Mime:         mime,
+		Size:         size,
+		SHA256:       shaHex,
+		CRC32:        crcHex,
+	}, nil
+}
+
+func hashFile(path string) (size int64, shaHex, crcHex string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", "", err
+	}
+	defer f.Close()
+
+	sha := sha256.New()
+	crc := crc32.NewIEEE()
+	written, err := io.Copy(io.MultiWriter(sha, crc), f)
+	if err != nil {
+		return 0, "", "", err
+	}
+	return written, hex.EncodeToString(sha.Sum(nil)), hex.EncodeToString(crc.Sum(nil)), nil
+}
+
+func verifyContentMD5(path, contentMD5 string) error {
+	want, err := base64.StdEncoding.DecodeString(contentMD5)
+	if err != nil {
+		return fmt.Errorf("invalid Content-MD5 header: %w", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+
+	got := h.Sum(nil)
+	if !bytes.Equal(got, want) {
+		return fmt.Errorf("computed md5 %x does not match Content-MD5", got)
+	}
+	return nil
+}
+
+func cleanupUploadedFiles(uploadPath string, files []uploadedFile) {
+	for _, f := range files {
+		os.Remove(filepath.Join(uploadPath, f.StoredName))
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// TestStreamPartToDiskHashMatchesStoredBytes guards the bug where the
+// returned SHA-256/Size described the bytes as uploaded rather than the
+// bytes scrubEXIF leaves on disk: every JPEG/PNG part gets decoded and
+// re-encoded after it's hashed, so the upload body below carries trailing
+// bytes scrubEXIF's decode step drops, forcing the stored file to differ
+// in both content and length from what was uploaded.
+func TestStreamPartToDiskHashMatchesStoredBytes(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	img.Set(1, 1, color.RGBA{R: 255, A: 255})
+
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatalf("encoding test jpeg: %v", err)
+	}
+	uploaded := append(encoded.Bytes(), bytes.Repeat([]byte{0xAA}, 64)...)
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("files", "photo.jpg")
+	if err != nil {
+		t.Fatalf("creating form file: %v", err)
+	}
+	if _, err := part.Write(uploaded); err != nil {
+		t.Fatalf("writing part: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("closing multipart writer: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	reader, err := req.MultipartReader()
+	if err != nil {
+		t.Fatalf("building multipart reader: %v", err)
+	}
+	mpPart, err := reader.NextPart()
+	if err != nil {
+		t.Fatalf("reading part: %v", err)
+	}
+	defer mpPart.Close()
+
+	uploadPath := t.TempDir()
+
+	result, err := streamPartToDisk(mpPart, uploadPath, defaultMaxUploadBytesPerPart, zap.NewNop())
+	if err != nil {
+		t.Fatalf("streamPartToDisk: %v", err)
+	}
+
+	stored, err := os.ReadFile(filepath.Join(uploadPath, result.StoredName))
+	if err != nil {
+		t.Fatalf("reading stored file: %v", err)
+	}
+	if bytes.Equal(stored, uploaded) {
+		t.Fatalf("scrubEXIF left the file byte-for-byte identical to the upload; this test can't catch hash staleness")
+	}
+
+	sum := sha256.Sum256(stored)
+	if want := hex.EncodeToString(sum[:]); result.SHA256 != want {
+		t.Fatalf("returned SHA256 %s does not match stored bytes hash %s", result.SHA256, want)
+	}
+	if result.Size != int64(len(stored)) {
+		t.Fatalf("returned Size %d does not match stored bytes length %d", result.Size, len(stored))
+	}
+}
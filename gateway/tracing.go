@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerServiceName = "nue-gateway"
+
+// initTracing wires OpenTelemetry when OTEL_EXPORTER_OTLP_ENDPOINT is
+// set, exporting spans over OTLP/gRPC. With no endpoint configured the
+// global tracer provider stays the OTel default no-op, so spans are
+// created but never exported. The returned shutdown func should be
+// deferred from main to flush and close the exporter.
+func initTracing(ctx context.Context) (func(context.Context) error, error) {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracegrpc.New(ctx, otlptracegrpc.WithEndpoint(endpoint), otlptracegrpc.WithInsecure())
+	if err != nil {
+		return nil, fmt.Errorf("creating otlp exporter: %w", err)
+	}
+
+	res, err := resource.New(ctx, resource.WithAttributes(semconv.ServiceName(tracerServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("building otel resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+
+	return tp.Shutdown, nil
+}
+
+func tracingMiddleware() gin.HandlerFunc {
+	return otelgin.Middleware(tracerServiceName)
+}
+
+// setUploadSpanAttributes annotates the request's active span with
+// details an upload handler only knows once it's finished - original
+// filename, stored uuid, byte count, and whether metadata was attached.
+func setUploadSpanAttributes(c *gin.Context, originalName, storedUUID string, bytes int64, hasMetadata bool) {
+	span := trace.SpanFromContext(c.Request.Context())
+	span.SetAttributes(
+		attribute.String("upload.original_filename", originalName),
+		attribute.String("upload.uuid", storedUUID),
+		attribute.Int64("upload.bytes", bytes),
+		attribute.Bool("upload.has_metadata", hasMetadata),
+	)
+}